@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/lib"
+)
+
+// inputFormat identifies which decoder a results file should be read with.
+type inputFormat string
+
+const (
+	formatAuto inputFormat = "auto"
+	formatGob  inputFormat = "gob"
+	formatJSON inputFormat = "json"
+	formatHDR  inputFormat = "hdr"
+)
+
+// detectFormat resolves "auto" to a concrete format by sniffing path's file
+// extension: ".json" decodes as vegeta's JSON result stream, ".hgrm"
+// decodes as an HdrHistogram percentile distribution log (as produced by
+// wrk2, k6, and friends), and anything else is assumed to be vegeta's gob
+// format. A non-"auto" format is returned unchanged.
+func detectFormat(format inputFormat, path string) inputFormat {
+	if format != formatAuto {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".hgrm":
+		return formatHDR
+	default:
+		return formatGob
+	}
+}
+
+// newResultDecoder returns the vegeta.Decoder appropriate for format. Only
+// formatGob and formatJSON are valid here; formatHDR is handled separately
+// by attackNameAndMetricsHDR since HDR logs aren't a stream of
+// vegeta.Results.
+func newResultDecoder(format inputFormat, f *os.File) vegeta.Decoder {
+	if format == formatJSON {
+		return vegeta.NewJSONDecoder(f)
+	}
+	return vegeta.NewDecoder(f)
+}
+
+// hgrmPercentileLine matches one data row of an HdrHistogram percentile
+// distribution log, e.g.:
+//
+//	1.234 0.500000 123 2.00
+//
+// columns are: value, percentile, total count, 1/(1-percentile).
+var hgrmPercentileLine = regexp.MustCompile(`^\s*([0-9.]+)\s+([0-9.]+)\s+(\d+)\s+([0-9.Inf]+)\s*$`)
+
+// attackNameAndMetricsHDR reads an HdrHistogram ".hgrm" percentile
+// distribution log and populates the latency percentiles of a
+// vegeta.Metrics from it. HDR logs carry no information about requests
+// per second, bytes transferred, or success ratio, so those fields are
+// left as NaN/0 and rendered as "n/a" by the writers. Values in the log
+// are assumed to be in milliseconds, matching wrk2/k6's default output.
+func attackNameAndMetricsHDR(path string) (string, *vegeta.Metrics, uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	var (
+		metrics    vegeta.Metrics
+		maxCount   uint64
+		sawPercent = map[float64]time.Duration{}
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := hgrmPercentileLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		valueMS, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		percentile, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseUint(m[3], 10, 64)
+		if err == nil && count > maxCount {
+			maxCount = count
+		}
+		sawPercent[percentile] = time.Duration(valueMS * float64(time.Millisecond))
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, 0, err
+	}
+
+	// Requests comes straight from the histogram's total count. Rate and
+	// Duration don't: a percentile distribution log has no wall-clock
+	// timing information (no start/end timestamp, no elapsed seconds), so
+	// there's nothing honest to derive them from. They're left unknown
+	// (durationUnknown / NaN) rather than guessed at, and rendered as
+	// "n/a" everywhere, same as Throughput/Bytes/Success below.
+	metrics.Requests = maxCount
+	metrics.Latencies.P50 = closestPercentile(sawPercent, 0.50)
+	metrics.Latencies.P95 = closestPercentile(sawPercent, 0.95)
+	metrics.Latencies.P99 = closestPercentile(sawPercent, 0.99)
+	metrics.Latencies.Max = closestPercentile(sawPercent, 1.0)
+	metrics.Latencies.Mean = meanPercentile(sawPercent)
+	metrics.Duration = durationUnknown
+	metrics.Rate = math.NaN()
+	metrics.Throughput = math.NaN()
+	metrics.Success = math.NaN()
+	metrics.BytesIn.Mean = math.NaN()
+	metrics.BytesOut.Mean = math.NaN()
+
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), &metrics, uint64(fi.Size()), nil
+}
+
+// durationUnknown marks a vegeta.Metrics.Duration as not available from the
+// input (e.g. an HDR percentile log, which carries no wall-clock timing).
+// time.Duration has no NaN, and a zero Duration is a legitimate value, so a
+// negative sentinel is used instead; formatDurationOrNA renders it.
+const durationUnknown time.Duration = -1
+
+// formatDurationOrNA renders d the way the CSV/XLSX writers display a test
+// duration, or "n/a" if d is durationUnknown.
+func formatDurationOrNA(d time.Duration) string {
+	if d == durationUnknown {
+		return "n/a"
+	}
+	return d.Round(3 * time.Second).String() // 1m58.999964907s -> 2m0s
+}
+
+// closestPercentile returns the recorded latency at the percentile nearest
+// to the requested one (above or below) from an HdrHistogram percentile
+// distribution log, or 0 if percentiles is empty.
+func closestPercentile(percentiles map[float64]time.Duration, want float64) time.Duration {
+	var best time.Duration
+	bestDelta := math.Inf(1)
+	for p, d := range percentiles {
+		delta := math.Abs(p - want)
+		if delta < bestDelta {
+			bestDelta, best = delta, d
+		}
+	}
+	return best
+}
+
+// meanPercentile approximates the mean latency from an HdrHistogram
+// percentile distribution log, since the log itself doesn't record a mean:
+// it averages the recorded percentile values as a rough stand-in.
+func meanPercentile(percentiles map[float64]time.Duration) time.Duration {
+	if len(percentiles) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range percentiles {
+		sum += d
+	}
+	return sum / time.Duration(len(percentiles))
+}
+
+// naPercent formats x (a fraction, e.g. vegeta.Metrics.Success) as a
+// percentage, or "n/a" if x is NaN (as set for metrics an input format like
+// HDR can't supply).
+func naPercent(x float64) string {
+	if math.IsNaN(x) {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", x*100.0)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		format inputFormat
+		path   string
+		want   inputFormat
+	}{
+		{formatAuto, "run.json", formatJSON},
+		{formatAuto, "run.hgrm", formatHDR},
+		{formatAuto, "RUN.HGRM", formatHDR},
+		{formatAuto, "run.gob", formatGob},
+		{formatAuto, "run", formatGob},
+		{formatJSON, "run.hgrm", formatJSON}, // explicit format wins over sniffing
+	}
+	for _, tt := range tests {
+		if got := detectFormat(tt.format, tt.path); got != tt.want {
+			t.Errorf("detectFormat(%q, %q) = %q, want %q", tt.format, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHgrmPercentileLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		match bool
+	}{
+		{"1.234 0.500000 123 2.00", true},
+		{"  1.234     0.500000      123     2.00  ", true},
+		{"Value Percentile TotalCount 1/(1-Percentile)", false},
+		{"#[Mean    =        1.234, StdDeviation   =        0.500]", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hgrmPercentileLine.MatchString(tt.line); got != tt.match {
+			t.Errorf("hgrmPercentileLine.MatchString(%q) = %v, want %v", tt.line, got, tt.match)
+		}
+	}
+}
+
+func TestClosestPercentile(t *testing.T) {
+	percentiles := map[float64]time.Duration{
+		0.50: 10 * time.Millisecond,
+		0.95: 50 * time.Millisecond,
+		0.99: 90 * time.Millisecond,
+	}
+	tests := []struct {
+		want float64
+		d    time.Duration
+	}{
+		{0.50, 10 * time.Millisecond},
+		{0.96, 50 * time.Millisecond}, // nearest below/above 0.95
+		{1.0, 90 * time.Millisecond},  // nearest is 0.99
+	}
+	for _, tt := range tests {
+		if got := closestPercentile(percentiles, tt.want); got != tt.d {
+			t.Errorf("closestPercentile(%v, %v) = %v, want %v", percentiles, tt.want, got, tt.d)
+		}
+	}
+	if got := closestPercentile(map[float64]time.Duration{}, 0.5); got != 0 {
+		t.Errorf("closestPercentile(empty) = %v, want 0", got)
+	}
+}
+
+func TestMeanPercentile(t *testing.T) {
+	percentiles := map[float64]time.Duration{
+		0.50: 10 * time.Millisecond,
+		0.95: 20 * time.Millisecond,
+	}
+	want := 15 * time.Millisecond
+	if got := meanPercentile(percentiles); got != want {
+		t.Errorf("meanPercentile(%v) = %v, want %v", percentiles, got, want)
+	}
+	if got := meanPercentile(nil); got != 0 {
+		t.Errorf("meanPercentile(nil) = %v, want 0", got)
+	}
+}
+
+func TestFormatDurationOrNA(t *testing.T) {
+	if got := formatDurationOrNA(durationUnknown); got != "n/a" {
+		t.Errorf("formatDurationOrNA(durationUnknown) = %q, want %q", got, "n/a")
+	}
+	if got := formatDurationOrNA(2 * time.Minute); got != "2m0s" {
+		t.Errorf("formatDurationOrNA(2m) = %q, want %q", got, "2m0s")
+	}
+}
+
+func TestNAPercent(t *testing.T) {
+	if got := naPercent(math.NaN()); got != "n/a" {
+		t.Errorf("naPercent(NaN) = %q, want %q", got, "n/a")
+	}
+	if got := naPercent(0.995); got != "99.5%" {
+		t.Errorf("naPercent(0.995) = %q, want %q", got, "99.5%")
+	}
+}
+
+func TestAttackNameAndMetricsHDR(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "search.hgrm")
+	content := "" +
+		"       Value     Percentile TotalCount 1/(1-Percentile)\n" +
+		"\n" +
+		"      10.000 0.500000000          50        2.00\n" +
+		"      50.000 0.950000000          95       20.00\n" +
+		"      90.000 0.990000000          99      100.00\n" +
+		"     100.000 1.000000000         100         Inf\n" +
+		"#[Mean    =       25.000, StdDeviation   =       20.000]\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	name, metrics, size, err := attackNameAndMetricsHDR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "search" {
+		t.Errorf("name = %q, want %q", name, "search")
+	}
+	if size == 0 {
+		t.Errorf("size = 0, want the file's size")
+	}
+	if metrics.Requests != 100 {
+		t.Errorf("Requests = %d, want 100", metrics.Requests)
+	}
+	if metrics.Latencies.P50 != 10*time.Millisecond {
+		t.Errorf("P50 = %v, want 10ms", metrics.Latencies.P50)
+	}
+	if metrics.Latencies.Max != 100*time.Millisecond {
+		t.Errorf("Max = %v, want 100ms", metrics.Latencies.Max)
+	}
+	if metrics.Duration != durationUnknown {
+		t.Errorf("Duration = %v, want durationUnknown (HDR logs carry no timing info)", metrics.Duration)
+	}
+	if !math.IsNaN(metrics.Throughput) {
+		t.Errorf("Throughput = %v, want NaN (HDR logs carry no throughput info)", metrics.Throughput)
+	}
+	if !math.IsNaN(metrics.Success) {
+		t.Errorf("Success = %v, want NaN (HDR logs carry no success-ratio info)", metrics.Success)
+	}
+}
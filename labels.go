@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// labelSegment matches one key=value segment embedded in a benchmark
+// filename, e.g. the "pkg=frontend" and "goos=linux" in
+// "search_pkg=frontend_goos=linux.gob".
+var labelSegment = regexp.MustCompile(`([A-Za-z0-9]+)=([^_.]+)`)
+
+// parseFilenameLabels splits a benchmark filename into its base name and
+// any trailing key=value segments, following benchstat's -split
+// convention. "search_pkg=frontend_goos=linux.gob" yields name "search"
+// and labels {"pkg": "frontend", "goos": "linux"}. A filename with no
+// key=value segments yields its whole (extension-stripped) name and no
+// labels.
+func parseFilenameLabels(filename string) (name string, labels map[string]string) {
+	stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+	loc := labelSegment.FindStringIndex(stem)
+	if loc == nil {
+		return stem, nil
+	}
+	name = strings.TrimSuffix(stem[:loc[0]], "_")
+	labels = map[string]string{}
+	for _, m := range labelSegment.FindAllStringSubmatch(stem[loc[0]:], -1) {
+		labels[m[1]] = m[2]
+	}
+	return name, labels
+}
+
+// loadSidecarLabels reads path's sidecar ".labels.json" file, if present,
+// e.g. "search.gob" pairs with "search.labels.json". It returns a nil map
+// (not an error) if no sidecar file exists.
+func loadSidecarLabels(path string) (map[string]string, error) {
+	sidecar := strings.TrimSuffix(path, filepath.Ext(path)) + ".labels.json"
+	data, err := os.ReadFile(sidecar)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// loadLabels returns the labels for a benchmark file, derived from its
+// filename (see parseFilenameLabels) and merged with any sidecar
+// ".labels.json" file, which takes precedence on key conflicts.
+func loadLabels(path string) (map[string]string, error) {
+	_, labels := parseFilenameLabels(filepath.Base(path))
+	sidecar, err := loadSidecarLabels(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(sidecar) == 0 {
+		return labels, nil
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range sidecar {
+		labels[k] = v
+	}
+	return labels, nil
+}
+
+// parseKeyValueList parses a comma-separated "key1=val1,key2=val2" string,
+// as used by -filter, into a map.
+func parseKeyValueList(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	result := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		result[kv[0]] = kv[1]
+	}
+	return result
+}
+
+// matchesFilter reports whether labels satisfies every key=value
+// constraint in filter. A label missing from labels reads as "", so a
+// benchmark with no labels satisfies an empty or nil filter, or one whose
+// values are all "".
+func matchesFilter(labels map[string]string, filter map[string]string) bool {
+	for k, v := range filter {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// labelTuple is one distinct combination of -split key values, e.g. for
+// "-split pkg,goos" the tuple {"pkg": "frontend", "goos": "linux"}.
+type labelTuple struct {
+	keys   []string
+	values map[string]string
+}
+
+// String renders the tuple as "pkg=frontend, goos=linux", in split-key
+// order, suitable as a section/sheet title.
+func (t labelTuple) String() string {
+	parts := make([]string, 0, len(t.keys))
+	for _, k := range t.keys {
+		parts = append(parts, k+"="+t.values[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// groupBySplit partitions benchmarks into one group per distinct
+// combination of splitKeys values, sorted by the tuple's String() so
+// output (sheet order, section order) is deterministic regardless of
+// input file order, mirroring benchstat's -split behavior. If splitKeys
+// is empty, all benchmarks are returned in a single group with an empty
+// tuple.
+func groupBySplit(benchmarks []benchmark, splitKeys []string) []struct {
+	tuple      labelTuple
+	benchmarks []benchmark
+} {
+	var order []string
+	groups := map[string][]benchmark{}
+	tuples := map[string]labelTuple{}
+	for _, b := range benchmarks {
+		values := map[string]string{}
+		for _, k := range splitKeys {
+			values[k] = b.labels[k]
+		}
+		t := labelTuple{keys: splitKeys, values: values}
+		key := t.String()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+			tuples[key] = t
+		}
+		groups[key] = append(groups[key], b)
+	}
+	sort.Strings(order)
+
+	result := make([]struct {
+		tuple      labelTuple
+		benchmarks []benchmark
+	}, 0, len(order))
+	for _, key := range order {
+		result = append(result, struct {
+			tuple      labelTuple
+			benchmarks []benchmark
+		}{tuples[key], groups[key]})
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilenameLabels(t *testing.T) {
+	tests := []struct {
+		filename   string
+		wantName   string
+		wantLabels map[string]string
+	}{
+		{"search.gob", "search", nil},
+		{"search_pkg=frontend_goos=linux.gob", "search", map[string]string{"pkg": "frontend", "goos": "linux"}},
+		{"search_pkg=frontend.json", "search", map[string]string{"pkg": "frontend"}},
+	}
+	for _, tt := range tests {
+		name, labels := parseFilenameLabels(tt.filename)
+		if name != tt.wantName {
+			t.Errorf("parseFilenameLabels(%q) name = %q, want %q", tt.filename, name, tt.wantName)
+		}
+		if !reflect.DeepEqual(labels, tt.wantLabels) {
+			t.Errorf("parseFilenameLabels(%q) labels = %v, want %v", tt.filename, labels, tt.wantLabels)
+		}
+	}
+}
+
+func TestLoadSidecarLabels(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "search.gob")
+	sidecar := filepath.Join(dir, "search.labels.json")
+
+	labels, err := loadSidecarLabels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels != nil {
+		t.Errorf("loadSidecarLabels(no sidecar) = %v, want nil", labels)
+	}
+
+	if err := os.WriteFile(sidecar, []byte(`{"pkg":"backend"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	labels, err = loadSidecarLabels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"pkg": "backend"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("loadSidecarLabels(sidecar) = %v, want %v", labels, want)
+	}
+}
+
+func TestParseKeyValueList(t *testing.T) {
+	tests := []struct {
+		s    string
+		want map[string]string
+	}{
+		{"", nil},
+		{"pkg=frontend", map[string]string{"pkg": "frontend"}},
+		{"pkg=frontend,goos=linux", map[string]string{"pkg": "frontend", "goos": "linux"}},
+		{"malformed", map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := parseKeyValueList(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeyValueList(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesFilter(t *testing.T) {
+	labels := map[string]string{"pkg": "frontend", "goos": "linux"}
+	tests := []struct {
+		filter map[string]string
+		want   bool
+	}{
+		{nil, true},
+		{map[string]string{"pkg": "frontend"}, true},
+		{map[string]string{"pkg": "backend"}, false},
+		{map[string]string{"pkg": "frontend", "goos": "darwin"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchesFilter(labels, tt.filter); got != tt.want {
+			t.Errorf("matchesFilter(%v, %v) = %v, want %v", labels, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestGroupBySplit(t *testing.T) {
+	benchmarks := []benchmark{
+		{name: "b", labels: map[string]string{"pkg": "backend"}},
+		{name: "a", labels: map[string]string{"pkg": "frontend"}},
+		{name: "c", labels: map[string]string{"pkg": "backend"}},
+	}
+	groups := groupBySplit(benchmarks, []string{"pkg"})
+	if len(groups) != 2 {
+		t.Fatalf("len(groups) = %d, want 2", len(groups))
+	}
+	// groupBySplit sorts by the tuple's String(), so "pkg=backend" sorts
+	// before "pkg=frontend" regardless of which benchmark was seen first.
+	if groups[0].tuple.String() != "pkg=backend" {
+		t.Errorf("groups[0].tuple = %q, want %q", groups[0].tuple.String(), "pkg=backend")
+	}
+	if len(groups[0].benchmarks) != 2 {
+		t.Errorf("len(groups[0].benchmarks) = %d, want 2", len(groups[0].benchmarks))
+	}
+	if groups[1].tuple.String() != "pkg=frontend" {
+		t.Errorf("groups[1].tuple = %q, want %q", groups[1].tuple.String(), "pkg=frontend")
+	}
+}
@@ -12,16 +12,25 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 	vegeta "github.com/tsenart/vegeta/lib"
+
+	"github.com/slimsag/kingkai/stats"
 )
 
 // attackNameAndMetrics gets the attack name (from the first result) and the
-// metrics for the given vegeta gob/.bin file.
-func attackNameAndMetrics(filepath string) (string, *vegeta.Metrics, uint64, error) {
-	f, err := os.Open(filepath)
+// metrics for the given results file, decoded according to format (see
+// detectFormat for the "auto" detection rules).
+func attackNameAndMetrics(path string, format inputFormat) (string, *vegeta.Metrics, uint64, error) {
+	format = detectFormat(format, path)
+	if format == formatHDR {
+		return attackNameAndMetricsHDR(path)
+	}
+
+	f, err := os.Open(path)
 	if err != nil {
 		return "", nil, 0, err
 	}
@@ -35,7 +44,7 @@ func attackNameAndMetrics(filepath string) (string, *vegeta.Metrics, uint64, err
 
 	// Read each result and add it to the metrics until EOF.
 	var (
-		d          = vegeta.NewDecoder(f)
+		d          = newResultDecoder(format, f)
 		attackName string
 		metrics    vegeta.Metrics
 	)
@@ -67,11 +76,20 @@ func percentageIncrease(before, after float64) float64 {
 type benchmark struct {
 	name          string
 	before, after *vegeta.Metrics
+	labels        map[string]string
 }
 
 var (
 	flagCSV  = flag.Bool("csv", false, "output comma seperated values (csv)")
 	flagXLSX = flag.Bool("xlsx", false, "output colored Google Sheets/Excel document (xlsx)")
+	flagHTML = flag.Bool("html", false, "output a self-contained HTML report with latency-distribution charts")
+
+	flagStats  = flag.Bool("stats", false, "output statistical significance testing across N runs (replaces -*-margin flags)")
+	flagAlpha  = flag.Float64("alpha", 0.05, "significance level for -stats (p < alpha is considered a real change)")
+	flagFormat = flag.String("format", string(formatAuto), "input format: gob, json, or hdr (default auto-detects from file extension)")
+
+	flagSplit  = flag.String("split", "", "comma-separated label keys to split the report into one section/sheet per distinct combination (e.g. \"pkg,goos\")")
+	flagFilter = flag.String("filter", "", "comma-separated key=value label constraints; only matching benchmarks are included")
 
 	flagProgress                = flag.Bool("progress", false, "print progress messages to stderr")
 	flagTotalRequestsMargin     = flag.Int("total-requests-margin", 0, "margin of error for total requests (in # requests)")
@@ -85,13 +103,48 @@ var (
 func main() {
 	// Flag parsing.
 	flag.Parse()
-	if flag.NArg() != 2 {
-		fmt.Printf("Usage: %s [-csv] before/ after/\n", os.Args[0])
+	if flag.NArg() < 2 {
+		fmt.Printf("Usage: %s [-csv] before/ after/ [more-runs/ ...]\n", os.Args[0])
 		os.Exit(1)
 	}
+
+	if flag.NArg() > 2 {
+		// Multi-way comparison: kingkai run1/ run2/ run3/ ..., each metric
+		// compared against the first (baseline) run. -stats and -html are
+		// two-way-only modes, so they're not supported here.
+		if *flagStats || *flagHTML {
+			log.Fatal("-stats and -html require exactly two directories (before/ after/); pass more for the default/-csv/-xlsx matrix report instead")
+		}
+		benchmarks, err := collectNWay(flag.Args(), inputFormat(*flagFormat))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *flagCSV {
+			writeCSVNWay(benchmarks)
+		} else if *flagXLSX {
+			writeXLSXNWay(benchmarks)
+		} else {
+			writeMarkdownNWay(benchmarks)
+		}
+		return
+	}
+
 	beforePath := flag.Arg(0)
 	afterPath := flag.Arg(1)
 
+	if *flagStats {
+		samples, requestsTotal, datasetTotal, err := collectSamples(beforePath, afterPath, *flagProgress)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *flagCSV {
+			writeCSVStats(samples, *flagAlpha)
+		} else {
+			writeXLSXStats(samples, *flagAlpha, requestsTotal, datasetTotal)
+		}
+		return
+	}
+
 	// Determine filenames (we just blindly assume after/ filenames match
 	// before/ filenames).
 	beforeDir, err := os.Open(beforePath)
@@ -120,19 +173,46 @@ func main() {
 		}
 	}
 
+	if *flagHTML {
+		var htmlBenchmarks []htmlBenchmark
+		for i, file := range commonFiles {
+			name, before, beforeResults, _, err := attackNameMetricsAndResults(filepath.Join(beforePath, file), inputFormat(*flagFormat))
+			if err != nil {
+				log.Fatal(filepath.Join(beforePath, file), err)
+			}
+			_, after, afterResults, _, err := attackNameMetricsAndResults(filepath.Join(afterPath, file), inputFormat(*flagFormat))
+			if err != nil {
+				log.Fatal(filepath.Join(afterPath, file), err)
+			}
+			if *flagProgress {
+				fmt.Fprintln(os.Stderr, "Consumed", i+1, "of", len(commonFiles), "files")
+			}
+			htmlBenchmarks = append(htmlBenchmarks, htmlBenchmark{
+				benchmark:     benchmark{name: name, before: before, after: after},
+				beforeResults: beforeResults,
+				afterResults:  afterResults,
+			})
+		}
+		sort.Slice(htmlBenchmarks, func(i, j int) bool {
+			return htmlBenchmarks[i].name < htmlBenchmarks[j].name
+		})
+		writeHTML(htmlBenchmarks)
+		return
+	}
+
 	// Read, decode, and sort the metrics.
 	var (
 		benchmarks                  []benchmark
 		datasetTotal, requestsTotal uint64
 	)
 	for i, file := range commonFiles {
-		name, before, fileSize, err := attackNameAndMetrics(filepath.Join(beforePath, file))
+		name, before, fileSize, err := attackNameAndMetrics(filepath.Join(beforePath, file), inputFormat(*flagFormat))
 		if err != nil {
 			log.Fatal(filepath.Join(beforePath, file), err)
 		}
 		datasetTotal += fileSize
 		requestsTotal += before.Requests
-		_, after, fileSize, err := attackNameAndMetrics(filepath.Join(afterPath, file))
+		_, after, fileSize, err := attackNameAndMetrics(filepath.Join(afterPath, file), inputFormat(*flagFormat))
 		if err != nil {
 			log.Fatal(filepath.Join(afterPath, file), err)
 		}
@@ -141,18 +221,48 @@ func main() {
 		if *flagProgress {
 			fmt.Fprintln(os.Stderr, "Consumed", datasetTotal, "bytes,", requestsTotal, "requests, from", i+1, "files")
 		}
-		benchmarks = append(benchmarks, benchmark{name, before, after})
+		labels, err := loadLabels(filepath.Join(beforePath, file))
+		if err != nil {
+			log.Fatal(filepath.Join(beforePath, file), err)
+		}
+		benchmarks = append(benchmarks, benchmark{name: name, before: before, after: after, labels: labels})
 	}
 	sort.Slice(benchmarks, func(i, j int) bool {
 		return benchmarks[i].name < benchmarks[j].name
 	})
 
+	if filter := parseKeyValueList(*flagFilter); len(filter) > 0 {
+		var filtered []benchmark
+		for _, b := range benchmarks {
+			if matchesFilter(b.labels, filter) {
+				filtered = append(filtered, b)
+			}
+		}
+		benchmarks = filtered
+	}
+
+	var splitKeys []string
+	if *flagSplit != "" {
+		splitKeys = strings.Split(*flagSplit, ",")
+	}
+	groups := groupBySplit(benchmarks, splitKeys)
+
 	if *flagCSV {
-		writeCSV(benchmarks)
-	} else if *flagXLSX {
+		for _, g := range groups {
+			writeCSV(g.benchmarks)
+		}
+	} else if *flagXLSX && len(splitKeys) == 0 {
 		writeXLSX(benchmarks, requestsTotal, datasetTotal)
+	} else if *flagXLSX {
+		writeXLSXGroups(groups, requestsTotal, datasetTotal)
 	} else {
-		writeMarkdown(benchmarks)
+		for _, g := range groups {
+			if len(splitKeys) > 0 {
+				fmt.Println("## " + g.tuple.String())
+				fmt.Println("")
+			}
+			writeMarkdown(g.benchmarks)
+		}
 	}
 }
 
@@ -185,8 +295,17 @@ func writeCSV(benchmarks []benchmark) {
 		return fmt.Sprintf("%.0f%%", percentageIncrease(float64(before), float64(after)))
 	}
 	formatPercentageIncreaseFloat := func(before, after float64) string {
+		if math.IsNaN(before) || math.IsNaN(after) {
+			return "n/a"
+		}
 		return fmt.Sprintf("%.0f%%", percentageIncrease(before, after))
 	}
+	formatOrNA := func(x float64) string {
+		if math.IsNaN(x) {
+			return "n/a"
+		}
+		return fmt.Sprint(x)
+	}
 
 	w.Write([]string{
 		"Name",
@@ -229,8 +348,8 @@ func writeCSV(benchmarks []benchmark) {
 		after := b.after.Latencies
 		w.Write([]string{
 			b.name,
-			fmt.Sprintf("%.0f", b.after.Rate),
-			b.after.Duration.Round(3 * time.Second).String(), // 1m58.999964907s -> 2m0s
+			formatOrNA(b.after.Rate),
+			formatDurationOrNA(b.after.Duration),
 			formatPercentageIncreaseFloat(b.before.BytesOut.Mean, b.after.BytesOut.Mean),
 			formatPercentageIncreaseFloat(b.before.BytesIn.Mean, b.after.BytesIn.Mean),
 			formatPercentageIncreaseFloat(b.before.Throughput, b.after.Throughput),
@@ -239,8 +358,8 @@ func writeCSV(benchmarks []benchmark) {
 			formatPercentageIncrease(before.P95, after.P95),
 			formatPercentageIncrease(before.P99, after.P99),
 			formatPercentageIncrease(before.Max, after.Max),
-			fmt.Sprintf("%.1f%%", b.before.Success*100.0),
-			fmt.Sprintf("%.1f%%", b.after.Success*100.0),
+			naPercent(b.before.Success),
+			naPercent(b.after.Success),
 			"",
 			smartFormat(before.Mean),
 			smartFormat(after.Mean),
@@ -253,29 +372,77 @@ func writeCSV(benchmarks []benchmark) {
 			smartFormat(before.Max),
 			smartFormat(after.Max),
 
-			fmt.Sprint(b.before.Throughput),
-			fmt.Sprint(b.after.Throughput),
+			formatOrNA(b.before.Throughput),
+			formatOrNA(b.after.Throughput),
 
 			fmt.Sprint(b.before.BytesOut.Total),
 			fmt.Sprint(b.after.BytesOut.Total),
-			fmt.Sprint(b.before.BytesOut.Mean),
-			fmt.Sprint(b.after.BytesOut.Mean),
+			formatOrNA(b.before.BytesOut.Mean),
+			formatOrNA(b.after.BytesOut.Mean),
 
 			fmt.Sprint(b.before.BytesIn.Total),
 			fmt.Sprint(b.after.BytesIn.Total),
-			fmt.Sprint(b.before.BytesIn.Mean),
-			fmt.Sprint(b.after.BytesIn.Mean),
+			formatOrNA(b.before.BytesIn.Mean),
+			formatOrNA(b.after.BytesIn.Mean),
 		})
 	}
 }
 
+// writeXLSX writes a single-sheet XLSX report comparing benchmarks, with
+// no label grouping. writeXLSXGroups is used instead when -split is given.
 func writeXLSX(benchmarks []benchmark, requestsTotal, datasetTotal uint64) {
 	f := excelize.NewFile()
 	sheet := "Sheet1"
 	results := f.NewSheet(sheet)
 	f.SetActiveSheet(results)
 	defer f.Write(os.Stdout)
+	writeXLSXSheet(f, sheet, benchmarks, requestsTotal, datasetTotal)
+}
+
+// writeXLSXGroups writes one XLSX sheet per label-split group, named after
+// its labelTuple (see groupBySplit / -split).
+func writeXLSXGroups(groups []struct {
+	tuple      labelTuple
+	benchmarks []benchmark
+}, requestsTotal, datasetTotal uint64) {
+	f := excelize.NewFile()
+	defaultSheet := f.GetSheetName(0)
+	for i, g := range groups {
+		sheet := sheetName(g.tuple, i)
+		idx := f.NewSheet(sheet)
+		if i == 0 {
+			f.SetActiveSheet(idx)
+		}
+		writeXLSXSheet(f, sheet, g.benchmarks, requestsTotal, datasetTotal)
+	}
+	if len(groups) > 0 {
+		f.DeleteSheet(defaultSheet)
+	}
+	f.Write(os.Stdout)
+}
 
+// sheetName derives a valid, unique-enough XLSX sheet name from a
+// labelTuple, falling back to "Sheet1", "Sheet2", ... (by position) when
+// there's no split (an empty tuple).
+func sheetName(t labelTuple, i int) string {
+	name := t.String()
+	if name == "" {
+		return fmt.Sprintf("Sheet%d", i+1)
+	}
+	// XLSX sheet names can't contain: \ / ? * [ ] : and are capped at 31
+	// characters.
+	replacer := strings.NewReplacer("\\", "_", "/", "_", "?", "_", "*", "_", "[", "_", "]", "_", ":", "_")
+	name = replacer.Replace(name)
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	return name
+}
+
+// writeXLSXSheet writes one benchmark comparison sheet into f, named
+// sheet. It holds the body of what was previously writeXLSX, factored out
+// so -split can render one sheet per label group.
+func writeXLSXSheet(f *excelize.File, sheet string, benchmarks []benchmark, requestsTotal, datasetTotal uint64) {
 	f.SetColWidth(sheet, "A", "A", 22)
 	f.SetColWidth(sheet, "B", "B", 19)
 	f.SetColWidth(sheet, "C", "D", 18)
@@ -352,66 +519,8 @@ func writeXLSX(benchmarks []benchmark, requestsTotal, datasetTotal uint64) {
 		before := b.before.Latencies
 		after := b.after.Latencies
 
-		equal := func(x, y, margin interface{}) bool {
-			switch xx := x.(type) {
-			case int:
-				yy := y.(int)
-				if xx > yy {
-					return (xx - yy) < margin.(int)
-				}
-				return (yy - xx) < margin.(int)
-			case uint64:
-				yy := y.(uint64)
-				if xx > yy {
-					return (xx - yy) < margin.(uint64)
-				}
-				return (yy - xx) < margin.(uint64)
-			case time.Duration:
-				yy := y.(time.Duration)
-				if xx > yy {
-					return (xx - yy) < margin.(time.Duration)
-				}
-				return (yy - xx) < margin.(time.Duration)
-			case float64:
-				yy := y.(float64)
-				if xx > yy {
-					return (xx - yy) < margin.(float64)
-				}
-				return (yy - xx) < margin.(float64)
-			default:
-				panic("never here")
-			}
-		}
-		greaterThan := func(x, y interface{}) bool {
-			switch xx := x.(type) {
-			case int:
-				return xx > y.(int)
-			case uint64:
-				return xx > y.(uint64)
-			case time.Duration:
-				return xx > y.(time.Duration)
-			case float64:
-				return xx > y.(float64)
-			default:
-				panic("never here")
-			}
-		}
 		setCellColor := func(cell string, before, after, margin interface{}, moreIsGood bool) {
-			moreStyle := green
-			lessStyle := red
-			if !moreIsGood {
-				moreStyle = red
-				lessStyle = green
-			}
-			if reflect.DeepEqual(before, after) {
-				f.SetCellStyle(sheet, cell, cell, gray)
-			} else if equal(before, after, margin) {
-				f.SetCellStyle(sheet, cell, cell, lightGray)
-			} else if greaterThan(after, before) {
-				f.SetCellStyle(sheet, cell, cell, moreStyle)
-			} else {
-				f.SetCellStyle(sheet, cell, cell, lessStyle)
-			}
+			xlsxSetChangeStyle(f, sheet, cell, before, after, margin, moreIsGood, green, lightGray, gray, red)
 		}
 		setCellIncrease := func(cell string, before, after, margin time.Duration, moreIsGood bool) {
 			f.SetCellValue(sheet, cell, fmt.Sprintf("%s", smartFormat(after-before)))
@@ -419,6 +528,13 @@ func writeXLSX(benchmarks []benchmark, requestsTotal, datasetTotal uint64) {
 			setCellColor(cell, before, after, margin, moreIsGood)
 		}
 		setCellIncreaseFloat := func(cell string, before, after, margin float64, nearestDecimal int, unit string, moreIsGood bool) {
+			if math.IsNaN(before) || math.IsNaN(after) {
+				// Not all input formats carry every metric (e.g. HDR logs
+				// have no throughput/byte-count data).
+				f.SetCellValue(sheet, cell, "n/a")
+				f.SetCellStyle(sheet, cell, cell, gray)
+				return
+			}
 			before = round(before, nearestDecimal)
 			after = round(after, nearestDecimal)
 			fmtString := "%0." + fmt.Sprint(nearestDecimal) + "f"
@@ -442,10 +558,87 @@ func writeXLSX(benchmarks []benchmark, requestsTotal, datasetTotal uint64) {
 		setCellIncreaseFloat(fmt.Sprintf("K%d", row), b.before.BytesIn.Mean, b.after.BytesIn.Mean, *flagMeanBytesReceivedMargin, 0, "bytes", true)
 		setCellIncreaseFloat(fmt.Sprintf("L%d", row), b.before.Success*100.0, b.after.Success*100.0, *flagSuccessMargin, 0, "percent", true)
 
-		beforeDuration := b.before.Duration.Round(3 * time.Second).String() // 1m58.999964907s -> 2m0s
-		afterDuration := b.after.Duration.Round(3 * time.Second).String()
+		beforeDuration := formatDurationOrNA(b.before.Duration)
+		afterDuration := formatDurationOrNA(b.after.Duration)
 		f.SetCellValue(sheet, fmt.Sprintf("M%d", row), afterDuration)
-		addComment(fmt.Sprintf("M%d", row), fmt.Sprintf("%v -> %v (%s)", beforeDuration, afterDuration, formatPercentageIncrease(b.before.Duration, b.after.Duration)))
+		if b.before.Duration == durationUnknown || b.after.Duration == durationUnknown {
+			addComment(fmt.Sprintf("M%d", row), fmt.Sprintf("%v -> %v", beforeDuration, afterDuration))
+		} else {
+			addComment(fmt.Sprintf("M%d", row), fmt.Sprintf("%v -> %v (%s)", beforeDuration, afterDuration, formatPercentageIncrease(b.before.Duration, b.after.Duration)))
+		}
+	}
+}
+
+// xlsxEqual reports whether x and y (both of the same type: int, uint64,
+// time.Duration, or float64) are within margin of each other.
+func xlsxEqual(x, y, margin interface{}) bool {
+	switch xx := x.(type) {
+	case int:
+		yy := y.(int)
+		if xx > yy {
+			return (xx - yy) < margin.(int)
+		}
+		return (yy - xx) < margin.(int)
+	case uint64:
+		yy := y.(uint64)
+		if xx > yy {
+			return (xx - yy) < margin.(uint64)
+		}
+		return (yy - xx) < margin.(uint64)
+	case time.Duration:
+		yy := y.(time.Duration)
+		if xx > yy {
+			return (xx - yy) < margin.(time.Duration)
+		}
+		return (yy - xx) < margin.(time.Duration)
+	case float64:
+		yy := y.(float64)
+		if xx > yy {
+			return (xx - yy) < margin.(float64)
+		}
+		return (yy - xx) < margin.(float64)
+	default:
+		panic("never here")
+	}
+}
+
+// xlsxGreaterThan reports whether x > y (both of the same type: int,
+// uint64, time.Duration, or float64).
+func xlsxGreaterThan(x, y interface{}) bool {
+	switch xx := x.(type) {
+	case int:
+		return xx > y.(int)
+	case uint64:
+		return xx > y.(uint64)
+	case time.Duration:
+		return xx > y.(time.Duration)
+	case float64:
+		return xx > y.(float64)
+	default:
+		panic("never here")
+	}
+}
+
+// xlsxSetChangeStyle colors cell for a before/after change: gray if
+// identical, lightGray if within margin, green/red depending on whether the
+// change is in the direction moreIsGood indicates. Shared by writeXLSXSheet
+// and writeXLSXNWay so both XLSX writers color changes the same way.
+func xlsxSetChangeStyle(f *excelize.File, sheet, cell string, before, after, margin interface{}, moreIsGood bool, green, lightGray, gray, red int) {
+	moreStyle := green
+	lessStyle := red
+	if !moreIsGood {
+		moreStyle = red
+		lessStyle = green
+	}
+	switch {
+	case reflect.DeepEqual(before, after):
+		f.SetCellStyle(sheet, cell, cell, gray)
+	case xlsxEqual(before, after, margin):
+		f.SetCellStyle(sheet, cell, cell, lightGray)
+	case xlsxGreaterThan(after, before):
+		f.SetCellStyle(sheet, cell, cell, moreStyle)
+	default:
+		f.SetCellStyle(sheet, cell, cell, lessStyle)
 	}
 }
 
@@ -466,6 +659,12 @@ func writeMarkdown(benchmarks []benchmark) {
 				percentageIncrease(float64(before), float64(after)),
 			)
 		}
+		formatSuccess := func(x float64) string {
+			if math.IsNaN(x) {
+				return "n/a"
+			}
+			return fmt.Sprintf("%.2f%%", x)
+		}
 
 		fmt.Println("### " + b.name)
 		fmt.Println("")
@@ -478,8 +677,311 @@ func writeMarkdown(benchmarks []benchmark) {
 			formatDurationDifference(b.before.Latencies.P95, b.after.Latencies.P95),
 			formatDurationDifference(b.before.Latencies.P99, b.after.Latencies.P99),
 			formatDurationDifference(b.before.Latencies.Max, b.after.Latencies.Max),
-			fmt.Sprintf("%.2f%% → %.2f%%", b.before.Success, b.after.Success),
+			fmt.Sprintf("%s → %s", formatSuccess(b.before.Success), formatSuccess(b.after.Success)),
 		)
 		fmt.Println("")
 	}
 }
+
+// statsMetric describes one of the metrics sampled across runs for -stats
+// mode: its key in metricSample.before/after, a human label, and whether it
+// is a latency (duration), for which an increase counts as worse rather
+// than better.
+type statsMetric struct {
+	key      string
+	label    string
+	duration bool
+}
+
+var statsMetrics = []statsMetric{
+	{"requests", "Total requests", false},
+	{"rate", "Request rate", false},
+	{"throughput", "Throughput", false},
+	{"mean", "Mean latency", true},
+	{"p50", "P50 latency", true},
+	{"p95", "P95 latency", true},
+	{"p99", "P99 latency", true},
+	{"max", "Max latency", true},
+	{"bytes_sent", "Mean bytes sent", false},
+	{"bytes_received", "Mean bytes received", false},
+	{"success", "Success ratio", false},
+}
+
+// metricSample holds, for a single benchmark, the per-run observations of
+// every statsMetric before and after the change being measured. Unlike
+// benchmark (which holds one aggregated vegeta.Metrics per side), each
+// metric here is a []float64 with one entry per run, so that -stats mode
+// can test whether the before/after difference is distinguishable from
+// run-to-run noise.
+type metricSample struct {
+	name          string
+	before, after map[string][]float64
+}
+
+// metricValues extracts the statsMetrics values out of m into a map keyed
+// by statsMetric.key.
+func metricValues(m *vegeta.Metrics) map[string]float64 {
+	return map[string]float64{
+		"requests":       float64(m.Requests),
+		"rate":           m.Rate,
+		"throughput":     m.Throughput,
+		"mean":           float64(m.Latencies.Mean),
+		"p50":            float64(m.Latencies.P50),
+		"p95":            float64(m.Latencies.P95),
+		"p99":            float64(m.Latencies.P99),
+		"max":            float64(m.Latencies.Max),
+		"bytes_sent":     m.BytesOut.Mean,
+		"bytes_received": m.BytesIn.Mean,
+		"success":        m.Success * 100.0,
+	}
+}
+
+// runDirs returns every subdirectory directly inside dir, sorted by name,
+// e.g. dir/1/, dir/2/, ... each expected to hold one results file per
+// benchmark from an independent run of the same benchmark set. Names are
+// not checked to be numbers.
+func runDirs(dir string) ([]string, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	fis, err := d.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, fi := range fis {
+		if fi.IsDir() {
+			dirs = append(dirs, filepath.Join(dir, fi.Name()))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// collectSamples walks beforePath/*/ and afterPath/*/ (one subdirectory per
+// independent run) and gathers, for every benchmark present in all run
+// directories, a metricSample of its before/after observations across runs.
+func collectSamples(beforePath, afterPath string, progress bool) ([]metricSample, uint64, uint64, error) {
+	beforeRuns, err := runDirs(beforePath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	afterRuns, err := runDirs(afterPath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(beforeRuns) == 0 || len(afterRuns) == 0 {
+		return nil, 0, 0, fmt.Errorf("-stats requires %s and %s to contain numbered run subdirectories (e.g. 1/, 2/, ...)", beforePath, afterPath)
+	}
+
+	samples := map[string]*metricSample{}
+	seen := map[string]int{} // number of run directories each file was found in
+	var order []string
+	var datasetTotal, requestsTotal uint64
+
+	gather := func(runs []string, side func(s *metricSample) map[string][]float64) error {
+		for _, run := range runs {
+			d, err := os.Open(run)
+			if err != nil {
+				return err
+			}
+			fis, err := d.Readdir(-1)
+			d.Close()
+			if err != nil {
+				return err
+			}
+			for _, fi := range fis {
+				if fi.IsDir() {
+					continue
+				}
+				name, metrics, fileSize, err := attackNameAndMetrics(filepath.Join(run, fi.Name()), inputFormat(*flagFormat))
+				if err != nil {
+					return err
+				}
+				datasetTotal += fileSize
+				requestsTotal += metrics.Requests
+
+				s, ok := samples[fi.Name()]
+				if !ok {
+					s = &metricSample{name: name, before: map[string][]float64{}, after: map[string][]float64{}}
+					samples[fi.Name()] = s
+					order = append(order, fi.Name())
+				}
+				seen[fi.Name()]++
+				values := side(s)
+				for key, v := range metricValues(metrics) {
+					values[key] = append(values[key], v)
+				}
+				if progress {
+					fmt.Fprintln(os.Stderr, "Consumed", datasetTotal, "bytes,", requestsTotal, "requests, from", run, fi.Name())
+				}
+			}
+		}
+		return nil
+	}
+	if err := gather(beforeRuns, func(s *metricSample) map[string][]float64 { return s.before }); err != nil {
+		return nil, 0, 0, err
+	}
+	if err := gather(afterRuns, func(s *metricSample) map[string][]float64 { return s.after }); err != nil {
+		return nil, 0, 0, err
+	}
+
+	sort.Strings(order)
+	result := make([]metricSample, 0, len(order))
+	for _, name := range order {
+		if seen[name] != len(beforeRuns)+len(afterRuns) {
+			continue
+		}
+		result = append(result, *samples[name])
+	}
+	return result, requestsTotal, datasetTotal, nil
+}
+
+// anyNaN reports whether any value in xs is NaN, e.g. because an input
+// format like HDR can't supply that metric.
+func anyNaN(xs []float64) bool {
+	for _, x := range xs {
+		if math.IsNaN(x) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatStatsCell renders a single -stats comparison as "delta% (p=0.03)",
+// along with whether the delta is a statistically significant change at
+// alpha (rather than indistinguishable from noise). If either sample
+// contains a NaN (the metric isn't measured by the input format, e.g.
+// throughput from an HDR log), it renders "n/a" rather than feeding NaN
+// into the significance test.
+func formatStatsCell(m statsMetric, before, after []float64, alpha float64) (text string, significant, worse bool) {
+	if anyNaN(before) || anyNaN(after) {
+		return "n/a", false, false
+	}
+	beforeMean, afterMean := stats.Mean(before), stats.Mean(after)
+	_, p := stats.MannWhitneyU(before, after)
+	delta := percentageIncrease(beforeMean, afterMean)
+	text = fmt.Sprintf("%+.1f%% (p=%.2f)", delta, p)
+	if p >= alpha {
+		return "~ " + text, false, false
+	}
+	// Higher latencies are worse; higher everything else (throughput,
+	// requests, success, ...) is better.
+	if m.duration {
+		worse = delta > 0
+	} else {
+		worse = delta < 0
+	}
+	return text, true, worse
+}
+
+func writeCSVStats(samples []metricSample, alpha float64) {
+	w := csv.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	header := []string{"Name"}
+	for _, m := range statsMetrics {
+		header = append(header, m.label+" change")
+	}
+	w.Write(header)
+
+	for _, s := range samples {
+		row := []string{s.name}
+		for _, m := range statsMetrics {
+			text, _, _ := formatStatsCell(m, s.before[m.key], s.after[m.key], alpha)
+			row = append(row, text)
+		}
+		w.Write(row)
+	}
+}
+
+func writeXLSXStats(samples []metricSample, alpha float64, requestsTotal, datasetTotal uint64) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	results := f.NewSheet(sheet)
+	f.SetActiveSheet(results)
+	defer f.Write(os.Stdout)
+
+	f.SetColWidth(sheet, "A", "A", 22)
+	f.SetColWidth(sheet, "B", string(rune('A'+len(statsMetrics))), 22)
+
+	bold, _ := f.NewStyle(`{"font":{"bold":true}}`)
+	green, _ := f.NewStyle(`{"fill":{"type":"pattern","color":["#29fd2e"],"pattern":1}}`)
+	gray, _ := f.NewStyle(`{"fill":{"type":"pattern","color":["#cccccc"],"pattern":1}}`)
+	red, _ := f.NewStyle(`{"fill":{"type":"pattern","color":["#fc0d1b"],"pattern":1}, "font":{"color": "#ffffff"}}`)
+	commaNumberStyle, _ := f.NewStyle(`{"custom_number_format": "#,##0"}`)
+	dataSizeStyle, _ := f.NewStyle(`{"custom_number_format": "[<1000000]0.00,\" KB\";[<1000000000]0.00,,\" MB\";0.00,,,\" GB\""}`)
+
+	f.SetCellValue(sheet, "A1", "Legend")
+	f.SetCellStyle(sheet, "A1", "A1", bold)
+	f.SetCellValue(sheet, "A2", fmt.Sprintf("Significant at p<%.2f, better", alpha))
+	f.SetCellStyle(sheet, "A2", "A2", green)
+	f.SetCellValue(sheet, "A3", fmt.Sprintf("Significant at p<%.2f, worse", alpha))
+	f.SetCellStyle(sheet, "A3", "A3", red)
+	f.SetCellValue(sheet, "A4", "Not statistically significant (\"~\")")
+	f.SetCellStyle(sheet, "A4", "A4", gray)
+	f.SetCellValue(sheet, "A5", "")
+
+	f.SetCellStyle(sheet, "C1", "C1", bold)
+	f.SetCellValue(sheet, "C1", "Dataset total")
+	f.SetCellStyle(sheet, "C2", "C2", dataSizeStyle)
+	f.SetCellValue(sheet, "C2", datasetTotal)
+	f.SetCellStyle(sheet, "D1", "D1", bold)
+	f.SetCellValue(sheet, "D1", "Requests total")
+	f.SetCellStyle(sheet, "D2", "D2", commaNumberStyle)
+	f.SetCellValue(sheet, "D2", requestsTotal)
+
+	row := 7
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "Name")
+	for i, m := range statsMetrics {
+		col := string(rune('B' + i))
+		f.SetCellValue(sheet, fmt.Sprintf("%s%d", col, row), m.label+" change")
+	}
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("Z%d", row), bold)
+
+	addComment := func(cell, comment string) {
+		d, _ := json.Marshal(struct{ Author, Text string }{Author: "Script: ", Text: comment})
+		f.AddComment(sheet, cell, string(d))
+	}
+
+	geomeans := map[string][]float64{}
+	for _, s := range samples {
+		row++
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), s.name)
+		for i, m := range statsMetrics {
+			col := string(rune('B' + i))
+			cell := fmt.Sprintf("%s%d", col, row)
+			text, significant, worse := formatStatsCell(m, s.before[m.key], s.after[m.key], alpha)
+			f.SetCellValue(sheet, cell, text)
+			if anyNaN(s.before[m.key]) || anyNaN(s.after[m.key]) {
+				addComment(cell, fmt.Sprintf("%d before-runs, %d after-runs; not measured by this input format",
+					len(s.before[m.key]), len(s.after[m.key])))
+			} else {
+				beforeMean, afterMean := stats.Mean(s.before[m.key]), stats.Mean(s.after[m.key])
+				addComment(cell, fmt.Sprintf("%d before-runs, %d after-runs; mean %v -> %v",
+					len(s.before[m.key]), len(s.after[m.key]), beforeMean, afterMean))
+				if beforeMean != 0 {
+					geomeans[m.key] = append(geomeans[m.key], afterMean/beforeMean)
+				}
+			}
+			switch {
+			case !significant:
+				f.SetCellStyle(sheet, cell, cell, gray)
+			case worse:
+				f.SetCellStyle(sheet, cell, cell, red)
+			default:
+				f.SetCellStyle(sheet, cell, cell, green)
+			}
+		}
+	}
+
+	row += 2
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "geomean")
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), bold)
+	for i, m := range statsMetrics {
+		col := string(rune('B' + i))
+		f.SetCellValue(sheet, fmt.Sprintf("%s%d", col, row), fmt.Sprintf("%+.1f%%", (stats.GeoMean(geomeans[m.key])-1)*100))
+	}
+}
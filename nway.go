@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+	vegeta "github.com/tsenart/vegeta/lib"
+
+	"github.com/slimsag/kingkai/stats"
+)
+
+// nWayBenchmark holds one benchmark's metrics across more than two
+// independent runs (kingkai run1/ run2/ run3/ ...). runs[0] is always the
+// baseline that every other run is compared against.
+type nWayBenchmark struct {
+	name string
+	runs []*vegeta.Metrics
+}
+
+// readDirFileNames lists the non-directory file names directly inside dir.
+func readDirFileNames(dir string) ([]string, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	fis, err := d.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+// collectNWay reads, for every benchmark file common to all of paths, its
+// metrics from each path in order (paths[0] is the baseline).
+func collectNWay(paths []string, format inputFormat) ([]nWayBenchmark, error) {
+	pathFiles := make([][]string, len(paths))
+	for i, p := range paths {
+		names, err := readDirFileNames(p)
+		if err != nil {
+			return nil, err
+		}
+		pathFiles[i] = names
+	}
+
+	var commonFiles []string
+	for _, name := range pathFiles[0] {
+		inAll := true
+		for _, names := range pathFiles[1:] {
+			found := false
+			for _, n := range names {
+				if n == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			commonFiles = append(commonFiles, name)
+		}
+	}
+
+	var benchmarks []nWayBenchmark
+	for _, file := range commonFiles {
+		var name string
+		runs := make([]*vegeta.Metrics, len(paths))
+		for i, p := range paths {
+			n, metrics, _, err := attackNameAndMetrics(filepath.Join(p, file), format)
+			if err != nil {
+				return nil, err
+			}
+			if name == "" {
+				name = n
+			}
+			runs[i] = metrics
+		}
+		benchmarks = append(benchmarks, nWayBenchmark{name: name, runs: runs})
+	}
+	sort.Slice(benchmarks, func(i, j int) bool { return benchmarks[i].name < benchmarks[j].name })
+	return benchmarks, nil
+}
+
+// nWayDelta renders the percentage change of metric m between a benchmark's
+// baseline run (runs[0]) and runs[runIndex], or "n/a" if either run's input
+// format doesn't supply m (e.g. HDR logs leave Throughput/Rate/Success/
+// BytesIn/BytesOut as NaN).
+func nWayDelta(b nWayBenchmark, runIndex int, m statsMetric) string {
+	before := metricValues(b.runs[0])[m.key]
+	after := metricValues(b.runs[runIndex])[m.key]
+	return formatChangeOrNA(before, after)
+}
+
+func writeCSVNWay(benchmarks []nWayBenchmark) {
+	w := csv.NewWriter(os.Stdout)
+	defer w.Flush()
+	if len(benchmarks) == 0 {
+		return
+	}
+	n := len(benchmarks[0].runs)
+
+	header := []string{"Name"}
+	for i := 1; i < n; i++ {
+		for _, m := range statsMetrics {
+			header = append(header, fmt.Sprintf("%s change (run %d vs baseline)", m.label, i+1))
+		}
+	}
+	w.Write(header)
+
+	for _, b := range benchmarks {
+		row := []string{b.name}
+		for i := 1; i < n; i++ {
+			for _, m := range statsMetrics {
+				row = append(row, nWayDelta(b, i, m))
+			}
+		}
+		w.Write(row)
+	}
+
+	writeGeomeanRowCSV(w, benchmarks)
+}
+
+// writeGeomeanRowCSV appends a trailing "geomean" row summarizing, per
+// run/metric column, the geometric mean of that column's ratios across all
+// benchmarks.
+func writeGeomeanRowCSV(w *csv.Writer, benchmarks []nWayBenchmark) {
+	if len(benchmarks) == 0 {
+		return
+	}
+	n := len(benchmarks[0].runs)
+	row := []string{"geomean"}
+	for i := 1; i < n; i++ {
+		for _, m := range statsMetrics {
+			var ratios []float64
+			for _, b := range benchmarks {
+				before := metricValues(b.runs[0])[m.key]
+				after := metricValues(b.runs[i])[m.key]
+				if !math.IsNaN(before) && !math.IsNaN(after) && before != 0 {
+					ratios = append(ratios, after/before)
+				}
+			}
+			row = append(row, fmt.Sprintf("%+.1f%%", (stats.GeoMean(ratios)-1)*100))
+		}
+	}
+	w.Write(row)
+}
+
+func writeMarkdownNWay(benchmarks []nWayBenchmark) {
+	if len(benchmarks) == 0 {
+		return
+	}
+	n := len(benchmarks[0].runs)
+
+	for _, b := range benchmarks {
+		fmt.Println("### " + b.name)
+		fmt.Println("")
+		header := "| Metric |"
+		sep := "|--------|"
+		for i := 1; i < n; i++ {
+			header += fmt.Sprintf(" run %d vs baseline |", i+1)
+			sep += "---------------------|"
+		}
+		fmt.Println(header)
+		fmt.Println(sep)
+		for _, m := range statsMetrics {
+			row := "| " + m.label + " |"
+			for i := 1; i < n; i++ {
+				row += " " + nWayDelta(b, i, m) + " |"
+			}
+			fmt.Println(row)
+		}
+		fmt.Println("")
+	}
+}
+
+// writeXLSXNWay writes a matrix report: one column group per non-baseline
+// run, each holding every statsMetric's percentage change versus the
+// baseline run, colored green/red/gray the same way writeXLSXSheet colors
+// its before/after columns. A trailing geomean row summarizes each column.
+func writeXLSXNWay(benchmarks []nWayBenchmark) {
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	defer f.Write(os.Stdout)
+
+	if len(benchmarks) == 0 {
+		return
+	}
+	n := len(benchmarks[0].runs)
+
+	bold, _ := f.NewStyle(`{"font":{"bold":true}}`)
+	green, _ := f.NewStyle(`{"fill":{"type":"pattern","color":["#29fd2e"],"pattern":1}}`)
+	gray, _ := f.NewStyle(`{"fill":{"type":"pattern","color":["#cccccc"],"pattern":1}}`)
+	red, _ := f.NewStyle(`{"fill":{"type":"pattern","color":["#fc0d1b"],"pattern":1}, "font":{"color": "#ffffff"}}`)
+	const noMargin float64 = 0
+
+	f.SetColWidth(sheet, "A", "A", 22)
+
+	col := func(i int) string {
+		// Column A is the benchmark name; metric columns start at B and
+		// are numbered from there, however many (n-1)*len(statsMetrics)
+		// turns out to be.
+		name, _ := excelize.ColumnNumberToName(i + 2)
+		return name
+	}
+
+	row := 1
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "Name")
+	colIndex := 0
+	for i := 1; i < n; i++ {
+		for _, m := range statsMetrics {
+			f.SetCellValue(sheet, fmt.Sprintf("%s%d", col(colIndex), row), fmt.Sprintf("run %d: %s change", i+1, m.label))
+			colIndex++
+		}
+	}
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", col(colIndex), row), bold)
+
+	for _, b := range benchmarks {
+		row++
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), b.name)
+		colIndex = 0
+		for i := 1; i < n; i++ {
+			for _, m := range statsMetrics {
+				cell := fmt.Sprintf("%s%d", col(colIndex), row)
+				before := metricValues(b.runs[0])[m.key]
+				after := metricValues(b.runs[i])[m.key]
+				moreIsGood := !m.duration
+				if math.IsNaN(before) || math.IsNaN(after) {
+					// Not all input formats carry every metric (e.g. HDR
+					// logs have no throughput/byte-count data).
+					f.SetCellValue(sheet, cell, "n/a")
+					f.SetCellStyle(sheet, cell, cell, gray)
+				} else {
+					f.SetCellValue(sheet, cell, fmt.Sprintf("%.0f%%", percentageIncrease(before, after)))
+					xlsxSetChangeStyle(f, sheet, cell, before, after, noMargin, moreIsGood, green, gray, gray, red)
+				}
+				colIndex++
+			}
+		}
+	}
+
+	row += 2
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "geomean")
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), bold)
+	colIndex = 0
+	for i := 1; i < n; i++ {
+		for _, m := range statsMetrics {
+			var ratios []float64
+			for _, b := range benchmarks {
+				before := metricValues(b.runs[0])[m.key]
+				after := metricValues(b.runs[i])[m.key]
+				if !math.IsNaN(before) && !math.IsNaN(after) && before != 0 {
+					ratios = append(ratios, after/before)
+				}
+			}
+			f.SetCellValue(sheet, fmt.Sprintf("%s%d", col(colIndex), row), fmt.Sprintf("%+.1f%%", (stats.GeoMean(ratios)-1)*100))
+			colIndex++
+		}
+	}
+}
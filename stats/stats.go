@@ -0,0 +1,139 @@
+// Package stats implements the small amount of statistical machinery kingkai
+// needs to decide whether a change in a noisy benchmark is real or just
+// noise, in the spirit of golang.org/x/perf/benchstat.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// MannWhitneyU runs a two-sided Mann-Whitney U test comparing the two
+// independent samples x and y. It returns the U statistic for x (the number
+// of pairs (xi, yj) where xi > yj, with ties counting as half a pair) and a
+// p-value computed via the normal approximation with a tie correction.
+//
+// The normal approximation is only accurate once len(x)+len(y) is
+// reasonably large (rule of thumb: > 20); for smaller samples the returned
+// p-value should be treated as a rough estimate rather than exact.
+func MannWhitneyU(x, y []float64) (u, p float64) {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type observation struct {
+		value float64
+		group int // 0 = x, 1 = y
+	}
+	obs := make([]observation, 0, n1+n2)
+	for _, v := range x {
+		obs = append(obs, observation{v, 0})
+	}
+	for _, v := range y {
+		obs = append(obs, observation{v, 1})
+	}
+	sort.Slice(obs, func(i, j int) bool { return obs[i].value < obs[j].value })
+
+	// Assign (fractional, tie-averaged) ranks and accumulate the tie
+	// correction term used by the normal approximation's variance.
+	ranks := make([]float64, len(obs))
+	var tieCorrection float64
+	for i := 0; i < len(obs); {
+		j := i + 1
+		for j < len(obs) && obs[j].value == obs[i].value {
+			j++
+		}
+		avgRank := float64(i+j+1) / 2 // 1-indexed average of ranks i+1..j
+		for k := i; k < j; k++ {
+			ranks[k] = avgRank
+		}
+		tiesInGroup := float64(j - i)
+		if tiesInGroup > 1 {
+			tieCorrection += tiesInGroup*tiesInGroup*tiesInGroup - tiesInGroup
+		}
+		i = j
+	}
+
+	var rankSumX float64
+	for i, o := range obs {
+		if o.group == 0 {
+			rankSumX += ranks[i]
+		}
+	}
+
+	nf1, nf2 := float64(n1), float64(n2)
+	u = rankSumX - nf1*(nf1+1)/2
+
+	nTotal := nf1 + nf2
+	meanU := nf1 * nf2 / 2
+	varU := nf1 * nf2 / 12 * ((nTotal + 1) - tieCorrection/(nTotal*(nTotal-1)))
+	if varU <= 0 {
+		// No spread at all (every value identical): treat as "no evidence
+		// of a difference" rather than dividing by zero.
+		return u, 1
+	}
+
+	// Continuity-corrected z score, then the two-sided normal p-value.
+	z := (u - meanU) / math.Sqrt(varU)
+	if u < meanU {
+		z += 0.5 / math.Sqrt(varU)
+	} else {
+		z -= 0.5 / math.Sqrt(varU)
+	}
+	p = 2 * (1 - normalCDF(math.Abs(z)))
+	if p > 1 {
+		p = 1
+	}
+	return u, p
+}
+
+// normalCDF returns the standard normal cumulative distribution function at
+// x, via the error function.
+func normalCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
+
+// Mean returns the arithmetic mean of xs, or 0 if xs is empty.
+func Mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// StdDev returns the sample standard deviation of xs, or 0 if xs has fewer
+// than two elements.
+func StdDev(xs []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	mean := Mean(xs)
+	var sumSq float64
+	for _, x := range xs {
+		d := x - mean
+		sumSq += d * d
+	}
+	return math.Sqrt(sumSq / float64(len(xs)-1))
+}
+
+// GeoMean returns the geometric mean of xs. It returns 0 if xs is empty or
+// contains a non-positive value, since the geometric mean is undefined
+// there.
+func GeoMean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sumLogs float64
+	for _, x := range xs {
+		if x <= 0 {
+			return 0
+		}
+		sumLogs += math.Log(x)
+	}
+	return math.Exp(sumLogs / float64(len(xs)))
+}
@@ -0,0 +1,106 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func approxEqual(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestMean(t *testing.T) {
+	tests := []struct {
+		name string
+		xs   []float64
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"single", []float64{5}, 5},
+		{"several", []float64{1, 2, 3, 4}, 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mean(tt.xs); !approxEqual(got, tt.want, 1e-9) {
+				t.Errorf("Mean(%v) = %v, want %v", tt.xs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	tests := []struct {
+		name string
+		xs   []float64
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"single", []float64{5}, 0},
+		{"two", []float64{2, 4}, math.Sqrt(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StdDev(tt.xs); !approxEqual(got, tt.want, 1e-9) {
+				t.Errorf("StdDev(%v) = %v, want %v", tt.xs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoMean(t *testing.T) {
+	tests := []struct {
+		name string
+		xs   []float64
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"contains non-positive", []float64{1, 0, 4}, 0},
+		{"powers of two", []float64{1, 2, 4, 8}, math.Pow(64, 0.25)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GeoMean(tt.xs); !approxEqual(got, tt.want, 1e-9) {
+				t.Errorf("GeoMean(%v) = %v, want %v", tt.xs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	t.Run("empty sample returns no evidence of a difference", func(t *testing.T) {
+		u, p := MannWhitneyU(nil, []float64{1, 2, 3})
+		if u != 0 || p != 1 {
+			t.Errorf("MannWhitneyU(nil, ...) = (%v, %v), want (0, 1)", u, p)
+		}
+	})
+
+	t.Run("identical distributions are not significant", func(t *testing.T) {
+		x := []float64{1, 2, 3, 4, 5}
+		y := []float64{1, 2, 3, 4, 5}
+		_, p := MannWhitneyU(x, y)
+		if p < 0.5 {
+			t.Errorf("MannWhitneyU(identical) p = %v, want >= 0.5", p)
+		}
+	})
+
+	t.Run("completely separated samples are significant", func(t *testing.T) {
+		x := []float64{1, 2, 3, 4, 5}
+		y := []float64{10, 11, 12, 13, 14}
+		u, p := MannWhitneyU(x, y)
+		if u != 0 {
+			t.Errorf("MannWhitneyU(separated) u = %v, want 0 (no xi > yj pairs)", u)
+		}
+		if p >= 0.05 {
+			t.Errorf("MannWhitneyU(separated) p = %v, want < 0.05", p)
+		}
+	})
+
+	t.Run("no variance returns no evidence of a difference", func(t *testing.T) {
+		x := []float64{3, 3, 3}
+		y := []float64{3, 3, 3}
+		_, p := MannWhitneyU(x, y)
+		if p != 1 {
+			t.Errorf("MannWhitneyU(no variance) p = %v, want 1", p)
+		}
+	})
+}